@@ -20,10 +20,11 @@ import (
 	"flag"
 	"github.com/knative/eventing/pkg/tracing"
 	"log"
+	"strings"
 	"time"
 
+	dispatcher "github.com/knative/eventing/pkg/channel/dispatcher"
 	informers "github.com/knative/eventing/pkg/client/informers/externalversions"
-	dispatcher "github.com/knative/eventing/pkg/inmemorychannel"
 	"github.com/knative/eventing/pkg/logconfig"
 	"github.com/knative/eventing/pkg/provisioners/swappable"
 	"github.com/knative/eventing/pkg/reconciler"
@@ -31,16 +32,30 @@ import (
 	"github.com/knative/pkg/configmap"
 	kncontroller "github.com/knative/pkg/controller"
 	"github.com/knative/pkg/logging"
+	"github.com/knative/pkg/metrics"
 	"github.com/knative/pkg/signals"
 	"go.uber.org/zap"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+const (
+	component = "imc_dispatcher"
+)
+
 var (
 	hardcodedLoggingConfig = flag.Bool("hardCodedLoggingConfig", false, "If true, use the hard coded logging config. It is intended to be used only when debugging outside a Kubernetes cluster.")
 	masterURL              = flag.String("master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
 	kubeconfig             = flag.String("kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
+	metricsPort            = flag.Int("metricsPort", 9090, "The port on which the Prometheus metrics exporter serves scrape requests.")
+	backend                = flag.String("backend", string(dispatcher.BackendInMemory), "The dispatcher backend to serve the Channel data plane with. One of: in-memory, kafka.")
+	kafkaBrokers           = flag.String("kafkaBrokers", "", "Comma-separated list of Kafka bootstrap brokers. Only used when --backend=kafka.")
+
+	leaderElectionNamespace = flag.String("leaderElectionNamespace", "knative-eventing", "The namespace the leader election Lease lives in.")
+	leaderElectionLeaseName = flag.String("leaderElectionLeaseName", "imc-dispatcher-controller", "The name of the leader election Lease.")
+	leaseDuration           = flag.Duration("leaseDuration", 15*time.Second, "The duration non-leader candidates wait before attempting to acquire leadership.")
+	renewDeadline           = flag.Duration("renewDeadline", 10*time.Second, "The duration the leader retries refreshing leadership before giving it up.")
+	retryPeriod             = flag.Duration("retryPeriod", 2*time.Second, "How long leader election clients wait between tries of actions.")
 
 	readTimeout  = 1 * time.Minute
 	writeTimeout = 1 * time.Minute
@@ -60,22 +75,27 @@ func main() {
 		logger.Fatalw("Error building kubeconfig", zap.Error(err))
 	}
 
-	sh, err := swappable.NewEmptyHandler(logger.Desugar())
+	eventsAtomicLevel := zap.NewAtomicLevel()
+	sh, err := swappable.NewEmptyHandler(logger.Desugar(), eventsAtomicLevel)
 	if err != nil {
 		logger.Fatal("Error creating swappable.Handler", zap.Error(err))
 	}
 
-	args := &dispatcher.InMemoryDispatcherArgs{
+	args := &dispatcher.Args{
 		Port:         port,
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writeTimeout,
 		Handler:      sh,
 		Logger:       logger.Desugar(),
+		KafkaBrokers: splitCSV(*kafkaBrokers),
+	}
+	channelDispatcher, err := dispatcher.NewDispatcher(dispatcher.Backend(*backend), args)
+	if err != nil {
+		logger.Fatalw("Error creating dispatcher", zap.String("backend", *backend), zap.Error(err))
 	}
-	inMemoryDispatcher := dispatcher.NewDispatcher(args)
 
 	logger = logger.With(zap.String("controller/impl", "pkg"))
-	logger.Info("Starting the InMemory dispatcher")
+	logger.Infow("Starting the dispatcher", zap.String("backend", *backend))
 
 	const numControllers = 1
 	cfg.QPS = numControllers * rest.DefaultQPS
@@ -93,8 +113,9 @@ func main() {
 	controllers := [...]*kncontroller.Impl{
 		inmemorychannel.NewController(
 			opt,
-			inMemoryDispatcher,
+			channelDispatcher,
 			inMemoryChannelInformer,
+			eventsAtomicLevel,
 		),
 	}
 	// This line asserts at compile time that the length of controllers is equal to numControllers.
@@ -105,9 +126,11 @@ func main() {
 	var _ [numControllers - len(controllers)][len(controllers) - numControllers]int
 
 	// Watch the logging config map and dynamically update logging levels.
-	opt.ConfigMapWatcher.Watch(logconfig.ConfigMapName(), logging.UpdateLevelFromConfigMap(logger, atomicLevel, logconfig.Controller))
-	// TODO: Watch the observability config map and dynamically update metrics exporter.
-	//opt.ConfigMapWatcher.Watch(metrics.ObservabilityConfigName, metrics.UpdateExporterFromConfigMap(component, logger))
+	opt.ConfigMapWatcher.Watch(logconfig.ConfigMapName(),
+		logging.UpdateLevelFromConfigMap(logger, atomicLevel, logconfig.Controller),
+		logging.UpdateLevelFromConfigMap(logger, eventsAtomicLevel, logconfig.DispatcherEvents))
+	// Watch the observability config map and dynamically update the metrics exporter.
+	opt.ConfigMapWatcher.Watch(metrics.ObservabilityConfigName, metrics.UpdateExporterFromConfigMap(component, *metricsPort, logger))
 
 	// Setup zipkin tracing.
 	if err = tracing.SetupDynamicZipkinPublishing(logger, opt.ConfigMapWatcher, "imc-dispatcher"); err != nil {
@@ -128,12 +151,35 @@ func main() {
 		logger.Fatalf("Failed to start informers: %v", err)
 	}
 
-	go inMemoryDispatcher.Start(stopCh)
+	// The data-plane dispatcher serves every replica, leader or not.
+	go channelDispatcher.Start(stopCh)
+
+	// Only the elected leader runs the reconciler's controllers, so that
+	// running multiple replicas for HA doesn't race on the same objects.
+	lec := reconciler.LeaderElectionConfig{
+		LeaseName:      *leaderElectionLeaseName,
+		LeaseNamespace: *leaderElectionNamespace,
+		LeaseDuration:  *leaseDuration,
+		RenewDeadline:  *renewDeadline,
+		RetryPeriod:    *retryPeriod,
+	}
+	if err := reconciler.RunLeaderElected(opt, lec, func(stopCh <-chan struct{}) {
+		logger.Info("Starting controllers.")
+		kncontroller.StartAll(stopCh, controllers[:]...)
+	}, stopCh); err != nil {
+		logger.Fatalw("Error running leader election", zap.Error(err))
+	}
 
-	logger.Info("Starting controllers.")
-	kncontroller.StartAll(stopCh, controllers[:]...)
+	channelDispatcher.Stop()
+}
 
-	inMemoryDispatcher.Stop()
+// splitCSV splits a comma-separated flag value into its components,
+// returning nil for an empty string.
+func splitCSV(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
 }
 
 func setupLogger() (*zap.SugaredLogger, zap.AtomicLevel) {
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dispatcher defines a transport-agnostic Dispatcher interface for
+// the channel dispatcher binaries, and a registry of backends (in-memory,
+// Kafka, ...) that implement it. This lets a single reconciler binary serve
+// Channels backed by different transports, selected at runtime.
+package dispatcher
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Backend identifies a pluggable dispatcher transport.
+type Backend string
+
+const (
+	// BackendInMemory dispatches events by holding them in process memory,
+	// with no durability guarantees across restarts.
+	BackendInMemory Backend = "in-memory"
+
+	// BackendKafka dispatches events through an Apache Kafka cluster,
+	// using topics as the underlying Channel transport.
+	BackendKafka Backend = "kafka"
+)
+
+// Dispatcher is implemented by every channel dispatcher backend. It serves
+// the data-plane HTTP ingress for Channels and fans events out to their
+// subscribers over whatever transport the backend provides.
+type Dispatcher interface {
+	// Start blocks serving the dispatcher's data plane until stopCh is
+	// closed.
+	Start(stopCh <-chan struct{}) error
+
+	// Stop releases any resources held by the dispatcher.
+	Stop()
+
+	// UpdateConfig installs handler as the configuration used to fan
+	// events out to Channel subscribers. It returns an error if this
+	// backend's Handler was not built to support dynamic reconfiguration.
+	UpdateConfig(handler http.Handler) error
+}
+
+// Args are the common arguments shared by every Dispatcher backend.
+type Args struct {
+	Port         int
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	Handler      http.Handler
+	Logger       *zap.Logger
+
+	// KafkaBrokers is the list of Kafka bootstrap brokers. Only consumed
+	// by the BackendKafka provider.
+	KafkaBrokers []string
+}
+
+// Provider constructs a Dispatcher from Args.
+type Provider func(args *Args) (Dispatcher, error)
+
+var providers = map[Backend]Provider{}
+
+// RegisterBackend makes a Dispatcher implementation available under name.
+// It is intended to be called from the init() function of the package
+// implementing the backend.
+func RegisterBackend(name Backend, provider Provider) {
+	providers[name] = provider
+}
+
+// NewDispatcher constructs the Dispatcher registered for backend, or returns
+// an error if no such backend has been registered.
+func NewDispatcher(backend Backend, args *Args) (Dispatcher, error) {
+	provider, ok := providers[backend]
+	if !ok {
+		return nil, fmt.Errorf("unknown dispatcher backend %q", backend)
+	}
+	return provider(args)
+}
+
+// ChannelFromPath recovers the Channel a request targets from its URL
+// path, which every backend routes as /<namespace>/<name>. It is exported
+// so that pkg/channel/fanout's MultiChannelHandler, which routes requests
+// the same way, can share this instead of keeping its own copy.
+func ChannelFromPath(path string) (namespace, name string, err error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("unaddressable channel path %q", path)
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatcher
+
+import "testing"
+
+func TestNewDispatcherUnknownBackend(t *testing.T) {
+	_, err := NewDispatcher(Backend("does-not-exist"), &Args{})
+	if err == nil {
+		t.Fatal("expected an error for an unregistered backend, got nil")
+	}
+}
+
+func TestNewDispatcherKnownBackends(t *testing.T) {
+	for _, backend := range []Backend{BackendInMemory, BackendKafka} {
+		if _, ok := providers[backend]; !ok {
+			t.Errorf("expected backend %q to be registered", backend)
+		}
+	}
+}
+
+func TestChannelFromPath(t *testing.T) {
+	tests := []struct {
+		path      string
+		namespace string
+		name      string
+		wantErr   bool
+	}{
+		{path: "/default/my-channel", namespace: "default", name: "my-channel"},
+		{path: "default/my-channel", namespace: "default", name: "my-channel"},
+		{path: "/default", wantErr: true},
+		{path: "/default/my-channel/extra", wantErr: true},
+		{path: "/", wantErr: true},
+	}
+	for _, tt := range tests {
+		ns, name, err := ChannelFromPath(tt.path)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ChannelFromPath(%q): expected error, got none", tt.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ChannelFromPath(%q): unexpected error: %v", tt.path, err)
+		}
+		if ns != tt.namespace || name != tt.name {
+			t.Errorf("ChannelFromPath(%q) = (%q, %q), want (%q, %q)", tt.path, ns, name, tt.namespace, tt.name)
+		}
+	}
+}
+
+func TestChannelTopicRoundTrip(t *testing.T) {
+	topic := kafkaTopicName("default", "my-channel")
+	ns, name := channelFromTopic(topic)
+	if ns != "default" || name != "my-channel" {
+		t.Errorf("channelFromTopic(%q) = (%q, %q), want (\"default\", \"my-channel\")", topic, ns, name)
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatcher
+
+import (
+	inmemorychannel "github.com/knative/eventing/pkg/inmemorychannel"
+)
+
+func init() {
+	RegisterBackend(BackendInMemory, newInMemoryDispatcher)
+}
+
+// newInMemoryDispatcher adapts the existing in-process inmemorychannel
+// dispatcher to the Dispatcher interface.
+func newInMemoryDispatcher(args *Args) (Dispatcher, error) {
+	return inmemorychannel.NewDispatcher(&inmemorychannel.InMemoryDispatcherArgs{
+		Port:         args.Port,
+		ReadTimeout:  args.ReadTimeout,
+		WriteTimeout: args.WriteTimeout,
+		Handler:      args.Handler,
+		Logger:       args.Logger,
+	}), nil
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.uber.org/zap"
+)
+
+func init() {
+	RegisterBackend(BackendKafka, newKafkaDispatcher)
+}
+
+// kafkaTopicPrefix namespaces the Kafka topics this dispatcher owns from
+// any other topics that might live on the same cluster.
+const kafkaTopicPrefix = "imc-channel-"
+
+// kafkaTopicName returns the Kafka topic a Channel's events are produced
+// to and consumed from.
+func kafkaTopicName(namespace, name string) string {
+	return kafkaTopicPrefix + namespace + "." + name
+}
+
+// kafkaDispatcher serves the Channel data plane over HTTP, publishing
+// accepted events to the sending Channel's Kafka topic, and fanning them
+// back out to subscribers from a consumer group that reads every
+// `imc-channel-*` topic on the cluster.
+type kafkaDispatcher struct {
+	args     *Args
+	logger   *zap.Logger
+	admin    sarama.ClusterAdmin
+	producer sarama.SyncProducer
+	consumer sarama.ConsumerGroup
+	ingress  *http.Server
+
+	cancel context.CancelFunc
+}
+
+func newKafkaDispatcher(args *Args) (Dispatcher, error) {
+	if len(args.KafkaBrokers) == 0 {
+		return nil, fmt.Errorf("kafka backend requires at least one broker")
+	}
+
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Return.Successes = true
+	config.Version = sarama.V2_0_0_0
+
+	producer, err := sarama.NewSyncProducer(args.KafkaBrokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create kafka producer: %w", err)
+	}
+
+	admin, err := sarama.NewClusterAdmin(args.KafkaBrokers, config)
+	if err != nil {
+		producer.Close()
+		return nil, fmt.Errorf("unable to create kafka cluster admin: %w", err)
+	}
+
+	consumer, err := sarama.NewConsumerGroup(args.KafkaBrokers, "imc-dispatcher", config)
+	if err != nil {
+		producer.Close()
+		admin.Close()
+		return nil, fmt.Errorf("unable to create kafka consumer group: %w", err)
+	}
+
+	d := &kafkaDispatcher{
+		args:     args,
+		logger:   args.Logger,
+		admin:    admin,
+		producer: producer,
+		consumer: consumer,
+	}
+	d.ingress = &http.Server{
+		Addr: fmt.Sprintf(":%d", args.Port),
+		Handler: &ochttp.Handler{
+			Handler:     http.HandlerFunc(d.serveIngress),
+			Propagation: &b3.HTTPFormat{},
+		},
+		ReadTimeout:  args.ReadTimeout,
+		WriteTimeout: args.WriteTimeout,
+	}
+	return d, nil
+}
+
+// serveIngress publishes an accepted CloudEvent to its Channel's Kafka
+// topic. The actual subscriber fan-out happens later, out of band, when
+// the consumer group reads the message back off that topic.
+func (d *kafkaDispatcher) serveIngress(w http.ResponseWriter, r *http.Request) {
+	namespace, name, err := ChannelFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "unable to read request body", http.StatusBadRequest)
+		return
+	}
+
+	headers := make([]sarama.RecordHeader, 0, len(r.Header))
+	for k, vs := range r.Header {
+		for _, v := range vs {
+			headers = append(headers, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+		}
+	}
+
+	msg := &sarama.ProducerMessage{
+		Topic:   kafkaTopicName(namespace, name),
+		Value:   sarama.ByteEncoder(body),
+		Headers: headers,
+	}
+	if _, _, err := d.producer.SendMessage(msg); err != nil {
+		d.logger.Error("Unable to publish event to Kafka", zap.String("topic", msg.Topic), zap.Error(err))
+		http.Error(w, "unable to publish event", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// consumerGroupHandler implements sarama.ConsumerGroupHandler, replaying
+// each Kafka message it reads as an HTTP request into the shared
+// swappable.Handler so the usual per-channel subscriber fan-out (retries,
+// dead lettering, metrics, audit logging) runs exactly as it would for the
+// in-memory backend.
+type consumerGroupHandler struct {
+	handler http.Handler
+	logger  *zap.Logger
+}
+
+func (consumerGroupHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (consumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (h consumerGroupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		namespace, name := channelFromTopic(msg.Topic)
+
+		req := httptest.NewRequest(http.MethodPost, "/"+namespace+"/"+name, bytes.NewReader(msg.Value))
+		for _, hdr := range msg.Headers {
+			req.Header.Add(string(hdr.Key), string(hdr.Value))
+		}
+
+		rec := httptest.NewRecorder()
+		h.handler.ServeHTTP(rec, req)
+		if rec.Code >= 300 {
+			h.logger.Error("Error fanning out Kafka-sourced event",
+				zap.String("topic", msg.Topic), zap.Int("status", rec.Code))
+		}
+		sess.MarkMessage(msg, "")
+	}
+	return nil
+}
+
+// channelFromTopic is the inverse of kafkaTopicName.
+func channelFromTopic(topic string) (namespace, name string) {
+	key := strings.TrimPrefix(topic, kafkaTopicPrefix)
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// channelTopics lists every `imc-channel-*` topic currently on the
+// cluster, i.e. every Channel this dispatcher backend is responsible for.
+func (d *kafkaDispatcher) channelTopics() ([]string, error) {
+	topics, err := d.admin.ListTopics()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for topic := range topics {
+		if strings.HasPrefix(topic, kafkaTopicPrefix) {
+			names = append(names, topic)
+		}
+	}
+	return names, nil
+}
+
+// Start implements Dispatcher. It serves the ingress HTTP server and runs
+// the consumer group loop, re-joining with the current set of Channel
+// topics whenever the consumer group session ends (e.g. because a Channel
+// was added or removed), until stopCh is closed.
+func (d *kafkaDispatcher) Start(stopCh <-chan struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
+	errCh := make(chan error, 1)
+	go func() {
+		d.logger.Info("Starting Kafka ingress HTTP server", zap.String("addr", d.ingress.Addr))
+		errCh <- d.ingress.ListenAndServe()
+	}()
+
+	go func() {
+		handler := consumerGroupHandler{handler: d.args.Handler, logger: d.logger}
+		for ctx.Err() == nil {
+			topics, err := d.channelTopics()
+			if err != nil {
+				d.logger.Error("Unable to list Kafka channel topics", zap.Error(err))
+				time.Sleep(5 * time.Second)
+				continue
+			}
+			if len(topics) == 0 {
+				time.Sleep(time.Second)
+				continue
+			}
+			if err := d.consumer.Consume(ctx, topics, handler); err != nil && ctx.Err() == nil {
+				d.logger.Error("Kafka consumer group session ended with error", zap.Error(err))
+			}
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-stopCh:
+		return nil
+	}
+}
+
+// configurableHandler is implemented by an http.Handler that supports
+// swapping its backing configuration at runtime, such as
+// swappable.Handler. Declaring it here, rather than importing swappable's
+// concrete type, lets any such handler be used without this package
+// importing swappable.
+type configurableHandler interface {
+	http.Handler
+	UpdateConfig(handler http.Handler)
+}
+
+// UpdateConfig implements Dispatcher by forwarding to the shared handler
+// every replayed Kafka message is served through. The ingress HTTP server
+// does not use that handler; it only publishes to Kafka.
+func (d *kafkaDispatcher) UpdateConfig(handler http.Handler) error {
+	configurable, ok := d.args.Handler.(configurableHandler)
+	if !ok {
+		return fmt.Errorf("kafka dispatcher's handler does not support dynamic reconfiguration")
+	}
+	configurable.UpdateConfig(handler)
+	return nil
+}
+
+// Stop implements Dispatcher.
+func (d *kafkaDispatcher) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	if err := d.ingress.Close(); err != nil {
+		d.logger.Error("Error closing Kafka ingress HTTP server", zap.Error(err))
+	}
+	if err := d.consumer.Close(); err != nil {
+		d.logger.Error("Error closing Kafka consumer group", zap.Error(err))
+	}
+	if err := d.producer.Close(); err != nil {
+		d.logger.Error("Error closing Kafka producer", zap.Error(err))
+	}
+	if err := d.admin.Close(); err != nil {
+		d.logger.Error("Error closing Kafka cluster admin", zap.Error(err))
+	}
+}
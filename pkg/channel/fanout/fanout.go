@@ -0,0 +1,213 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fanout builds the per-Channel http.Handler that fans an inbound
+// CloudEvent out to each of a Channel's subscribers: it retries delivery to
+// a subscriber up to its configured budget, and once that budget is
+// exhausted forwards the event on to the subscriber's deadLetterSink, if it
+// has one. This is the one place that actually knows which subscriber an
+// event is being delivered to.
+package fanout
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/knative/eventing/pkg/inmemorychannel"
+	"go.uber.org/zap"
+)
+
+// Subscription is everything the fanout Handler needs to know to deliver to,
+// and if necessary dead-letter, one of a Channel's subscribers.
+type Subscription struct {
+	// Name identifies the subscription, e.g. its Subscription object's
+	// "namespace/name".
+	Name string
+
+	// SubscriberURI is the resolved address events are POSTed to.
+	SubscriberURI string
+
+	// DeadLetterSinkURI is the resolved address events are POSTed to once
+	// the first delivery attempt to SubscriberURI and all Retries
+	// redeliveries have failed. Empty if the subscription has no
+	// deadLetterSink configured.
+	DeadLetterSinkURI string
+
+	// Retries is the number of redelivery attempts to make to
+	// SubscriberURI after the first, before giving up and dead lettering.
+	Retries int
+}
+
+// Handler is the http.Handler installed into the dispatcher's
+// MultiChannelHandler for a single Channel. It is rebuilt and reinstalled
+// whole by the reconciler whenever that Channel's subscribers change.
+type Handler struct {
+	Namespace     string
+	Channel       string
+	Subscriptions []Subscription
+
+	Reporter   inmemorychannel.StatsReporter
+	Audit      *inmemorychannel.EventAuditLogger
+	DeadLetter *inmemorychannel.DeadLetterDispatcher
+	Logger     *zap.Logger
+
+	Client *http.Client
+
+	// inFlight tracks, per subscription name, how many deliveries to that
+	// subscriber are currently outstanding, so ReportInFlight can report an
+	// up-to-date gauge value rather than a delta.
+	inFlight map[string]*int64
+}
+
+// New returns a fanout Handler for a single Channel's current subscribers.
+func New(namespace, channel string, subs []Subscription, reporter inmemorychannel.StatsReporter, audit *inmemorychannel.EventAuditLogger, deadLetter *inmemorychannel.DeadLetterDispatcher, logger *zap.Logger) *Handler {
+	inFlight := make(map[string]*int64, len(subs))
+	for _, sub := range subs {
+		var n int64
+		inFlight[sub.Name] = &n
+	}
+	return &Handler{
+		Namespace:     namespace,
+		Channel:       channel,
+		Subscriptions: subs,
+		Reporter:      reporter,
+		Audit:         audit,
+		DeadLetter:    deadLetter,
+		Logger:        logger,
+		Client:        http.DefaultClient,
+		inFlight:      inFlight,
+	}
+}
+
+// ServeHTTP implements http.Handler, delivering the request body to every
+// subscriber in turn. It responds 202 once every subscriber has either
+// accepted the event or exhausted its retries (and been dead lettered, if
+// configured); delivery failures are not surfaced to the sender, which
+// matches a Channel's usual fire-and-forget fan-out semantics.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "unable to read request body", http.StatusBadRequest)
+		return
+	}
+
+	for _, sub := range h.Subscriptions {
+		h.dispatch(r, body, sub)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// dispatch delivers body to a single subscriber, retrying up to
+// sub.Retries times, and forwards to the subscriber's dead letter sink once
+// those retries are exhausted.
+func (h *Handler) dispatch(r *http.Request, body []byte, sub Subscription) {
+	args := inmemorychannel.ReportArgs{
+		Namespace:  h.Namespace,
+		Channel:    h.Channel,
+		Subscriber: sub.Name,
+	}
+
+	counter := h.inFlight[sub.Name]
+	h.reportInFlight(r, args, atomic.AddInt64(counter, 1))
+	defer func() { h.reportInFlight(r, args, atomic.AddInt64(counter, -1)) }()
+
+	start := time.Now()
+	status := 0
+	for attempt := 0; ; attempt++ {
+		resp, err := h.attempt(r, body, sub.SubscriberURI)
+		if err != nil {
+			status = http.StatusBadGateway
+		} else {
+			status = resp
+		}
+		h.Audit.LogForward(r, sub.SubscriberURI, status, attempt)
+
+		if status >= 200 && status < 300 {
+			if err := h.Reporter.ReportEventDispatch(r.Context(), args, time.Since(start)); err != nil {
+				h.Logger.Sugar().Errorw("Unable to report dispatch metric", zap.Error(err))
+			}
+			return
+		}
+		if attempt >= sub.Retries {
+			break
+		}
+		if err := h.Reporter.ReportRetry(r.Context(), args); err != nil {
+			h.Logger.Sugar().Errorw("Unable to report retry metric", zap.Error(err))
+		}
+		time.Sleep(retryBackoff(attempt))
+	}
+
+	if err := h.Reporter.ReportDeliveryError(r.Context(), args); err != nil {
+		h.Logger.Sugar().Errorw("Unable to report delivery error metric", zap.Error(err))
+	}
+
+	if sub.DeadLetterSinkURI == "" {
+		h.Logger.Warn("Exhausted retries with no deadLetterSink configured, dropping event",
+			zap.String("subscriber", sub.SubscriberURI))
+		return
+	}
+	if err := h.DeadLetter.Dispatch(r.Context(), sub.DeadLetterSinkURI, body, r.Header, sub.SubscriberURI, status); err != nil {
+		h.Logger.Error("Unable to dispatch event to dead letter sink",
+			zap.String("sink", sub.DeadLetterSinkURI), zap.Error(err))
+	}
+}
+
+// retryBackoffBase and retryBackoffMax bound the delay between redelivery
+// attempts: the base value doubles with each attempt, capped at the max, so
+// a struggling subscriber isn't hammered back-to-back as fast as the
+// network allows before an event is dead lettered.
+const (
+	retryBackoffBase = 100 * time.Millisecond
+	retryBackoffMax  = 5 * time.Second
+)
+
+// retryBackoff returns how long to wait before redelivery attempt number
+// attempt+1 (attempt is 0 for the delay after the first failed attempt).
+func retryBackoff(attempt int) time.Duration {
+	d := retryBackoffBase << uint(attempt)
+	if d <= 0 || d > retryBackoffMax {
+		return retryBackoffMax
+	}
+	return d
+}
+
+func (h *Handler) reportInFlight(r *http.Request, args inmemorychannel.ReportArgs, count int64) {
+	if err := h.Reporter.ReportInFlight(r.Context(), args, count); err != nil {
+		h.Logger.Sugar().Errorw("Unable to report in-flight metric", zap.Error(err))
+	}
+}
+
+// attempt makes a single delivery attempt to uri, returning the HTTP status
+// code of the response.
+func (h *Handler) attempt(r *http.Request, body []byte, uri string) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, uri, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(r.Context())
+	req.Header = r.Header.Clone()
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, nil
+}
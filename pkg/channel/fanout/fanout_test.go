@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fanout
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/knative/eventing/pkg/inmemorychannel"
+	"go.uber.org/zap"
+)
+
+func newTestHandler(subs []Subscription) *Handler {
+	logger := zap.NewNop()
+	return New("default", "my-channel", subs,
+		inmemorychannel.NewStatsReporter(),
+		inmemorychannel.NewEventAuditLogger(logger, zap.NewAtomicLevel()),
+		inmemorychannel.NewDeadLetterDispatcher(),
+		logger)
+}
+
+// spyReporter records the duration passed to the last ReportEventDispatch
+// call, so tests can assert on what the real dispatch call site actually
+// reports rather than on a hand-supplied value.
+type spyReporter struct {
+	mu               sync.Mutex
+	dispatchDuration time.Duration
+}
+
+func (s *spyReporter) ReportEventDispatch(_ context.Context, _ inmemorychannel.ReportArgs, d time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatchDuration = d
+	return nil
+}
+func (s *spyReporter) ReportRetry(context.Context, inmemorychannel.ReportArgs) error { return nil }
+func (s *spyReporter) ReportDeliveryError(context.Context, inmemorychannel.ReportArgs) error {
+	return nil
+}
+func (s *spyReporter) ReportInFlight(context.Context, inmemorychannel.ReportArgs, int64) error {
+	return nil
+}
+
+func TestDispatchRetriesThenSucceeds(t *testing.T) {
+	var attempts int32
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer subscriber.Close()
+
+	subs := []Subscription{{Name: "default/sub", SubscriberURI: subscriber.URL, Retries: 5}}
+	h := newTestHandler(subs)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/default/my-channel", nil)
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("subscriber received %d attempts, want 3", got)
+	}
+}
+
+func TestDispatchReportsRealDispatchDuration(t *testing.T) {
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer subscriber.Close()
+
+	reporter := &spyReporter{}
+	logger := zap.NewNop()
+	h := New("default", "my-channel",
+		[]Subscription{{Name: "default/sub", SubscriberURI: subscriber.URL, Retries: 2}},
+		reporter,
+		inmemorychannel.NewEventAuditLogger(logger, zap.NewAtomicLevel()),
+		inmemorychannel.NewDeadLetterDispatcher(),
+		logger)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/default/my-channel", nil)
+	h.ServeHTTP(rec, req)
+
+	reporter.mu.Lock()
+	defer reporter.mu.Unlock()
+	if reporter.dispatchDuration <= 0 {
+		t.Errorf("ReportEventDispatch received duration = %v, want > 0", reporter.dispatchDuration)
+	}
+}
+
+func TestDispatchExhaustsRetriesAndDeadLetters(t *testing.T) {
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer subscriber.Close()
+
+	var deadLettered int32
+	deadLetterSink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&deadLettered, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer deadLetterSink.Close()
+
+	subs := []Subscription{{
+		Name:              "default/sub",
+		SubscriberURI:     subscriber.URL,
+		DeadLetterSinkURI: deadLetterSink.URL,
+		Retries:           2,
+	}}
+	h := newTestHandler(subs)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/default/my-channel", nil)
+	h.ServeHTTP(rec, req)
+
+	if got := atomic.LoadInt32(&deadLettered); got != 1 {
+		t.Errorf("dead letter sink received %d requests, want 1", got)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fanout
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	channeldispatcher "github.com/knative/eventing/pkg/channel/dispatcher"
+)
+
+// MultiChannelHandler is the single http.Handler installed into the data
+// plane Dispatcher for the lifetime of the process. It routes each inbound
+// request, addressed as /<namespace>/<name>, to the per-Channel Handler
+// most recently set for that Channel by the reconciler, so that
+// reconciling one Channel never disturbs any other Channel's
+// configuration.
+type MultiChannelHandler struct {
+	mu       sync.RWMutex
+	channels map[string]*Handler
+}
+
+// NewMultiChannelHandler returns an empty MultiChannelHandler. Channels are
+// added and removed from it with SetChannel and RemoveChannel as they are
+// reconciled.
+func NewMultiChannelHandler() *MultiChannelHandler {
+	return &MultiChannelHandler{channels: make(map[string]*Handler)}
+}
+
+// SetChannel installs handler as the configuration used to fan events out
+// for the Channel identified by namespace/name, replacing whatever was
+// previously installed for it.
+func (m *MultiChannelHandler) SetChannel(namespace, name string, handler *Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.channels[key(namespace, name)] = handler
+}
+
+// RemoveChannel stops routing requests for the Channel identified by
+// namespace/name.
+func (m *MultiChannelHandler) RemoveChannel(namespace, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.channels, key(namespace, name))
+}
+
+// ServeHTTP implements http.Handler.
+func (m *MultiChannelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	namespace, name, err := channeldispatcher.ChannelFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	m.mu.RLock()
+	handler, ok := m.channels[key(namespace, name)]
+	m.mu.RUnlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown channel %s/%s", namespace, name), http.StatusNotFound)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+func key(namespace, name string) string {
+	return namespace + "/" + name
+}
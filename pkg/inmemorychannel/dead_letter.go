@@ -0,0 +1,66 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inmemorychannel
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// DeadLetterDispatcher forwards a CloudEvent whose subscription exhausted
+// its retry budget to the subscription's deadLetterSink, the same address
+// the per-channel reconciler resolves from a Subscription's Ref/URI the way
+// it already resolves subscriber addresses.
+type DeadLetterDispatcher struct {
+	client *http.Client
+}
+
+// NewDeadLetterDispatcher returns a DeadLetterDispatcher using a default
+// http.Client.
+func NewDeadLetterDispatcher() *DeadLetterDispatcher {
+	return &DeadLetterDispatcher{client: http.DefaultClient}
+}
+
+// Dispatch POSTs body to sinkURI, carrying over the original CloudEvent's
+// headers, plus the knativeerrordest/knativeerrorcode extension attributes
+// describing why the event landed in the dead letter sink.
+func (d *DeadLetterDispatcher) Dispatch(ctx context.Context, sinkURI string, body []byte, headers http.Header, errDest string, errCode int) error {
+	req, err := http.NewRequest(http.MethodPost, sinkURI, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to build dead letter request: %w", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header = headers.Clone()
+	req.Header.Set("ce-knativeerrordest", errDest)
+	req.Header.Set("ce-knativeerrorcode", strconv.Itoa(errCode))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to dispatch to dead letter sink %q: %w", sinkURI, err)
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("dead letter sink %q returned status %d", sinkURI, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inmemorychannel
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+// fakeRoundTripper records the last request it served and returns a fixed
+// response, so Dispatch can be tested without a real HTTP server.
+type fakeRoundTripper struct {
+	status      int
+	lastRequest *http.Request
+	lastBody    []byte
+}
+
+func (f *fakeRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.lastRequest = req
+	if req.Body != nil {
+		f.lastBody, _ = ioutil.ReadAll(req.Body)
+	}
+	return &http.Response{
+		StatusCode: f.status,
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+		Header:     make(http.Header),
+	}, nil
+}
+
+func TestDeadLetterDispatcherDispatch(t *testing.T) {
+	rt := &fakeRoundTripper{status: http.StatusOK}
+	d := &DeadLetterDispatcher{client: &http.Client{Transport: rt}}
+
+	headers := http.Header{"Ce-Id": []string{"abc-123"}}
+	body := []byte(`{"hello":"world"}`)
+	if err := d.Dispatch(context.Background(), "http://dead-letter.example.com/", body, headers, "http://subscriber.example.com/", http.StatusInternalServerError); err != nil {
+		t.Fatalf("Dispatch() = %v", err)
+	}
+
+	if got := rt.lastRequest.Header.Get("Ce-Id"); got != "abc-123" {
+		t.Errorf("original header Ce-Id = %q, want %q", got, "abc-123")
+	}
+	if got := rt.lastRequest.Header.Get("ce-knativeerrordest"); got != "http://subscriber.example.com/" {
+		t.Errorf("ce-knativeerrordest = %q, want %q", got, "http://subscriber.example.com/")
+	}
+	if got := rt.lastRequest.Header.Get("ce-knativeerrorcode"); got != "500" {
+		t.Errorf("ce-knativeerrorcode = %q, want %q", got, "500")
+	}
+	if string(rt.lastBody) != string(body) {
+		t.Errorf("forwarded body = %q, want %q", rt.lastBody, body)
+	}
+}
+
+func TestDeadLetterDispatcherDispatchSinkError(t *testing.T) {
+	rt := &fakeRoundTripper{status: http.StatusInternalServerError}
+	d := &DeadLetterDispatcher{client: &http.Client{Transport: rt}}
+
+	err := d.Dispatch(context.Background(), "http://dead-letter.example.com/", nil, http.Header{}, "http://subscriber.example.com/", http.StatusInternalServerError)
+	if err == nil {
+		t.Fatal("expected an error when the dead letter sink itself fails, got nil")
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inmemorychannel
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.uber.org/zap"
+)
+
+// InMemoryDispatcherArgs configures a Dispatcher that serves a Channel's
+// data plane entirely in process, with no durability across restarts.
+type InMemoryDispatcherArgs struct {
+	Port         int
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	Handler      http.Handler
+	Logger       *zap.Logger
+}
+
+// ConfigurableHandler is implemented by the http.Handler supplied in
+// InMemoryDispatcherArgs when it also supports swapping its backing
+// subscriber configuration at runtime, such as swappable.Handler. Declaring
+// it here, rather than importing swappable.Handler's concrete type, is what
+// lets pkg/provisioners/swappable depend on this package (for
+// StatsReporter, EventAuditLogger, DeadLetterDispatcher) without creating an
+// import cycle back.
+type ConfigurableHandler interface {
+	http.Handler
+	UpdateConfig(handler http.Handler)
+}
+
+// Dispatcher serves the in-memory Channel data plane: an HTTP server in
+// front of the Handler supplied in InMemoryDispatcherArgs, which is
+// responsible for subscriber fan-out.
+type Dispatcher struct {
+	server       *http.Server
+	logger       *zap.Logger
+	configurable ConfigurableHandler
+}
+
+// NewDispatcher creates a Dispatcher from args.
+func NewDispatcher(args *InMemoryDispatcherArgs) *Dispatcher {
+	configurable, _ := args.Handler.(ConfigurableHandler)
+	return &Dispatcher{
+		server: &http.Server{
+			Addr: fmt.Sprintf(":%d", args.Port),
+			// Wrapping args.Handler in ochttp.Handler extracts the B3 trace
+			// context a sending client propagated (or starts a new span if
+			// none was sent) onto the request's context, so everything
+			// downstream, including EventAuditLogger's trace-id/span-id
+			// fields, has a real span to read from.
+			Handler: &ochttp.Handler{
+				Handler:     args.Handler,
+				Propagation: &b3.HTTPFormat{},
+			},
+			ReadTimeout:  args.ReadTimeout,
+			WriteTimeout: args.WriteTimeout,
+		},
+		logger:       args.Logger,
+		configurable: configurable,
+	}
+}
+
+// UpdateConfig reconfigures the handler's backing subscriber configuration,
+// if it supports doing so dynamically. It returns an error if it doesn't,
+// e.g. because the Handler this Dispatcher was built with never implemented
+// ConfigurableHandler in the first place.
+func (d *Dispatcher) UpdateConfig(handler http.Handler) error {
+	if d.configurable == nil {
+		return fmt.Errorf("dispatcher's handler does not support dynamic reconfiguration")
+	}
+	d.configurable.UpdateConfig(handler)
+	return nil
+}
+
+// Start serves the Channel data plane until stopCh is closed.
+func (d *Dispatcher) Start(stopCh <-chan struct{}) error {
+	errCh := make(chan error, 1)
+	go func() {
+		d.logger.Info("Starting in-memory dispatcher HTTP server", zap.String("addr", d.server.Addr))
+		errCh <- d.server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-stopCh:
+		return nil
+	}
+}
+
+// Stop shuts down the Channel data plane's HTTP server.
+func (d *Dispatcher) Stop() {
+	if err := d.server.Close(); err != nil {
+		d.logger.Error("Error closing in-memory dispatcher HTTP server", zap.Error(err))
+	}
+}
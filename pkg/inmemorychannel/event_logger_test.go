@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inmemorychannel
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestEventAuditLoggerIncludesTraceFieldsWhenSpanPresent(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+	audit := NewEventAuditLogger(logger, zap.NewAtomicLevelAt(zap.InfoLevel))
+
+	ctx, span := trace.StartSpan(httptest.NewRequest("POST", "/default/my-channel", nil).Context(), "test")
+	defer span.End()
+	req := httptest.NewRequest("POST", "/default/my-channel", nil).WithContext(ctx)
+
+	audit.LogReceived(req)
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+
+	sc := span.SpanContext()
+	fields := entries[0].ContextMap()
+	if got := fields["trace-id"]; got != sc.TraceID.String() {
+		t.Errorf("trace-id = %v, want %v", got, sc.TraceID.String())
+	}
+	if got := fields["span-id"]; got != sc.SpanID.String() {
+		t.Errorf("span-id = %v, want %v", got, sc.SpanID.String())
+	}
+}
+
+func TestEventAuditLoggerOmitsTraceFieldsWhenNoSpan(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+	audit := NewEventAuditLogger(logger, zap.NewAtomicLevelAt(zap.InfoLevel))
+
+	audit.LogReceived(httptest.NewRequest("POST", "/default/my-channel", nil))
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+	if _, ok := entries[0].ContextMap()["trace-id"]; ok {
+		t.Errorf("expected no trace-id field without a span in context")
+	}
+}
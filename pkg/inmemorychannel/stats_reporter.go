@@ -0,0 +1,200 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inmemorychannel
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// Tag keys used to break down the dispatcher's metrics by channel.
+var (
+	namespaceTagKey  = mustNewTagKey("namespace")
+	channelTagKey    = mustNewTagKey("channel_name")
+	subscriberTagKey = mustNewTagKey("subscriber_name")
+)
+
+var (
+	// dispatchCountM records the number of events the dispatcher has
+	// successfully delivered to a subscriber.
+	dispatchCountM = stats.Int64(
+		"event_count",
+		"Number of events dispatched to a subscriber",
+		stats.UnitDimensionless)
+
+	// dispatchTimeM records how long it took to forward an event to a
+	// subscriber, end to end, including retries.
+	dispatchTimeM = stats.Float64(
+		"dispatch_latency",
+		"The time spent dispatching an event to a subscriber",
+		stats.UnitMilliseconds)
+
+	// retryCountM records one for every redelivery attempt made to a
+	// subscriber after a failed delivery.
+	retryCountM = stats.Int64(
+		"retry_count",
+		"Number of delivery retries for an event",
+		stats.UnitDimensionless)
+
+	// deliveryErrorCountM records events that were not successfully
+	// delivered after exhausting the retry budget.
+	deliveryErrorCountM = stats.Int64(
+		"delivery_error_count",
+		"Number of events that failed delivery after all retries",
+		stats.UnitDimensionless)
+
+	// inFlightM is a gauge of the number of event dispatches currently in
+	// flight to subscribers.
+	inFlightM = stats.Int64(
+		"in_flight_dispatches",
+		"Number of in-flight event dispatches",
+		stats.UnitDimensionless)
+)
+
+func mustNewTagKey(name string) tag.Key {
+	k, err := tag.NewKey(name)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+func init() {
+	register := []*view.View{
+		{
+			Name:        "event_count",
+			Description: dispatchCountM.Description(),
+			Measure:     dispatchCountM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{namespaceTagKey, channelTagKey, subscriberTagKey},
+		},
+		{
+			Name:        "dispatch_latency",
+			Description: dispatchTimeM.Description(),
+			Measure:     dispatchTimeM,
+			Aggregation: view.Distribution(0, 10, 50, 100, 200, 500, 1000, 5000, 10000, 30000),
+			TagKeys:     []tag.Key{namespaceTagKey, channelTagKey, subscriberTagKey},
+		},
+		{
+			Name:        "retry_count",
+			Description: retryCountM.Description(),
+			Measure:     retryCountM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{namespaceTagKey, channelTagKey, subscriberTagKey},
+		},
+		{
+			Name:        "delivery_error_count",
+			Description: deliveryErrorCountM.Description(),
+			Measure:     deliveryErrorCountM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{namespaceTagKey, channelTagKey, subscriberTagKey},
+		},
+		{
+			Name:        "in_flight_dispatches",
+			Description: inFlightM.Description(),
+			Measure:     inFlightM,
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{namespaceTagKey, channelTagKey, subscriberTagKey},
+		},
+	}
+	if err := view.Register(register...); err != nil {
+		panic(err)
+	}
+}
+
+// StatsReporter reports metrics for the in-memory channel dispatcher's event
+// delivery path.
+type StatsReporter interface {
+	// ReportEventDispatch records that an event was delivered to a
+	// subscriber, and how long delivery took, including any retries.
+	ReportEventDispatch(ctx context.Context, args ReportArgs, d time.Duration) error
+
+	// ReportRetry records that an event delivery had to be retried.
+	ReportRetry(ctx context.Context, args ReportArgs) error
+
+	// ReportDeliveryError records that an event was not delivered to its
+	// subscriber after the retry budget was exhausted.
+	ReportDeliveryError(ctx context.Context, args ReportArgs) error
+
+	// ReportInFlight updates the gauge of in-flight dispatches to one of a
+	// channel's subscribers.
+	ReportInFlight(ctx context.Context, args ReportArgs, count int64) error
+}
+
+// ReportArgs identifies the channel and subscriber a metric applies to.
+type ReportArgs struct {
+	Namespace  string
+	Channel    string
+	Subscriber string
+}
+
+type reporter struct{}
+
+// NewStatsReporter creates a new StatsReporter which reports the in-memory
+// channel dispatcher's OpenCensus metrics.
+func NewStatsReporter() StatsReporter {
+	return &reporter{}
+}
+
+func (r *reporter) context(args ReportArgs) (context.Context, error) {
+	return tag.New(
+		context.Background(),
+		tag.Insert(namespaceTagKey, args.Namespace),
+		tag.Insert(channelTagKey, args.Channel),
+		tag.Insert(subscriberTagKey, args.Subscriber),
+	)
+}
+
+func (r *reporter) ReportEventDispatch(ctx context.Context, args ReportArgs, d time.Duration) error {
+	c, err := r.context(args)
+	if err != nil {
+		return err
+	}
+	stats.Record(c, dispatchCountM.M(1), dispatchTimeM.M(float64(d.Nanoseconds())/1e6))
+	return nil
+}
+
+func (r *reporter) ReportRetry(ctx context.Context, args ReportArgs) error {
+	c, err := r.context(args)
+	if err != nil {
+		return err
+	}
+	stats.Record(c, retryCountM.M(1))
+	return nil
+}
+
+func (r *reporter) ReportDeliveryError(ctx context.Context, args ReportArgs) error {
+	c, err := r.context(args)
+	if err != nil {
+		return err
+	}
+	stats.Record(c, deliveryErrorCountM.M(1))
+	return nil
+}
+
+func (r *reporter) ReportInFlight(ctx context.Context, args ReportArgs, count int64) error {
+	c, err := r.context(args)
+	if err != nil {
+		return err
+	}
+	stats.Record(c, inFlightM.M(count))
+	return nil
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inmemorychannel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats/view"
+)
+
+func TestStatsReporter(t *testing.T) {
+	r := NewStatsReporter()
+	args := ReportArgs{Namespace: "default", Channel: "my-channel", Subscriber: "default/my-sub"}
+
+	if err := r.ReportEventDispatch(context.Background(), args, 10*time.Millisecond); err != nil {
+		t.Errorf("ReportEventDispatch() = %v", err)
+	}
+	if err := r.ReportRetry(context.Background(), args); err != nil {
+		t.Errorf("ReportRetry() = %v", err)
+	}
+	if err := r.ReportDeliveryError(context.Background(), args); err != nil {
+		t.Errorf("ReportDeliveryError() = %v", err)
+	}
+	if err := r.ReportInFlight(context.Background(), args, 3); err != nil {
+		t.Errorf("ReportInFlight() = %v", err)
+	}
+
+	for _, name := range []string{"event_count", "retry_count", "delivery_error_count", "in_flight_dispatches"} {
+		if view.Find(name) == nil {
+			t.Errorf("expected view %q to be registered", name)
+		}
+		rows, err := view.RetrieveData(name)
+		if err != nil {
+			t.Errorf("RetrieveData(%q) = %v", name, err)
+			continue
+		}
+		if len(rows) == 0 {
+			t.Errorf("RetrieveData(%q) returned no rows after reporting", name)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logconfig centralizes the names used to look up per-component
+// logging configuration from the config-logging ConfigMap.
+package logconfig
+
+const (
+	// Controller is the component name the IMC dispatcher's own
+	// controller logger is registered under, i.e. the "loglevel.controller"
+	// key in the config-logging ConfigMap.
+	Controller = "controller"
+
+	// DispatcherEvents is the component name for the dispatcher's verbose
+	// per-event CloudEvents delivery audit log, i.e. the
+	// "loglevel.dispatcher.events" key in the config-logging ConfigMap.
+	// Operators can flip this at runtime to turn on a structured log line
+	// for every event received and forwarded by the dispatcher, without a
+	// restart.
+	DispatcherEvents = "dispatcher.events"
+)
+
+// ConfigMapName is the name of the ConfigMap that holds the logging
+// configuration consumed by this package's keys.
+func ConfigMapName() string {
+	return "config-logging"
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package swappable provides an http.Handler whose backing configuration of
+// Channels to subscribers can be atomically swapped out in response to
+// changes to the set of provisioned InMemoryChannels.
+package swappable
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/knative/eventing/pkg/inmemorychannel"
+	"go.uber.org/zap"
+)
+
+// Handler is an http.Handler that logs every inbound CloudEvent before
+// delegating to whatever configuration was most recently installed with
+// UpdateConfig. The configuration may be swapped out from underneath the
+// Handler at any time, which is how the reconciler installs an up-to-date
+// fanout.MultiChannelHandler whenever the set of InMemoryChannels, or their
+// subscribers, changes.
+//
+// The installed handler is expected to be a fanout.MultiChannelHandler,
+// which is where per-Channel routing and per-subscriber concerns like
+// retries, dead lettering, and metrics/audit logging actually happen; this
+// Handler only knows that a CloudEvent arrived, not which Channel or
+// subscriber it is ultimately bound for.
+type Handler struct {
+	logger *zap.Logger
+	audit  *inmemorychannel.EventAuditLogger
+
+	mux     sync.RWMutex
+	handler http.Handler
+}
+
+// NewEmptyHandler creates a new swappable Handler with no backing
+// configuration installed. UpdateConfig must be called before any requests
+// can be usefully served. eventsLevel gates the verbose per-event
+// CloudEvents audit log and is expected to be kept in sync with the
+// logconfig.DispatcherEvents config-logging key.
+func NewEmptyHandler(logger *zap.Logger, eventsLevel zap.AtomicLevel) (*Handler, error) {
+	if logger == nil {
+		return nil, errors.New("logger must not be nil")
+	}
+	return &Handler{
+		logger:  logger,
+		audit:   inmemorychannel.NewEventAuditLogger(logger, eventsLevel),
+		handler: http.HandlerFunc(http.NotFound),
+	}, nil
+}
+
+// UpdateConfig atomically swaps the handler currently used to serve requests
+// for the given one.
+func (h *Handler) UpdateConfig(handler http.Handler) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.handler = handler
+}
+
+// ServeHTTP implements http.Handler, recording a structured audit log entry
+// when enabled, before delegating to the currently installed configuration.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.RLock()
+	handler := h.handler
+	h.mux.RUnlock()
+
+	h.audit.LogReceived(r)
+	handler.ServeHTTP(w, r)
+}
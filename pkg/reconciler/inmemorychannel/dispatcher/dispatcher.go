@@ -0,0 +1,189 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dispatcher reconciles InMemoryChannels by resolving each of their
+// subscribers' deadLetterSinks to an address and keeping the data-plane
+// Dispatcher's installed fanout.MultiChannelHandler in sync with the
+// result, so that a change to a Channel's subscribers is reflected the
+// next time an event is fanned out, without restarting the dispatcher.
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/knative/eventing/pkg/apis/messaging/v1alpha1"
+	channeldispatcher "github.com/knative/eventing/pkg/channel/dispatcher"
+	"github.com/knative/eventing/pkg/channel/fanout"
+	messaginginformers "github.com/knative/eventing/pkg/client/informers/externalversions/messaging/v1alpha1"
+	messaginglisters "github.com/knative/eventing/pkg/client/listers/messaging/v1alpha1"
+	"github.com/knative/eventing/pkg/inmemorychannel"
+	"github.com/knative/eventing/pkg/reconciler"
+	"github.com/knative/pkg/controller"
+	"github.com/knative/pkg/resolver"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/cache"
+)
+
+const controllerAgentName = "imc-dispatcher-controller"
+
+// NewController returns a controller.Impl that reconciles InMemoryChannels
+// by keeping ceDispatcher's installed fanout.MultiChannelHandler in sync
+// with each Channel's subscribers. eventsLevel gates the fanout handlers'
+// per-event audit log and is expected to be kept in sync with the
+// logconfig.DispatcherEvents config-logging key, the same as
+// swappable.Handler's.
+func NewController(
+	opt reconciler.Options,
+	ceDispatcher channeldispatcher.Dispatcher,
+	imcInformer messaginginformers.InMemoryChannelInformer,
+	eventsLevel zap.AtomicLevel,
+) *controller.Impl {
+	multiChannel := fanout.NewMultiChannelHandler()
+	if err := ceDispatcher.UpdateConfig(multiChannel); err != nil {
+		opt.Logger.Fatalw("Unable to install the fanout handler into the dispatcher", zap.Error(err))
+	}
+
+	r := &Reconciler{
+		Base:         reconciler.NewBase(opt, controllerAgentName),
+		imcLister:    imcInformer.Lister(),
+		multiChannel: multiChannel,
+		reporter:     inmemorychannel.NewStatsReporter(),
+		audit:        inmemorychannel.NewEventAuditLogger(opt.Logger.Desugar(), eventsLevel),
+		deadLetters:  inmemorychannel.NewDeadLetterDispatcher(),
+	}
+
+	impl := controller.NewImpl(r, r.Logger, "ImcDispatcher")
+	r.sinkResolver = resolver.NewURIResolver(opt.Logger, impl.EnqueueKey)
+
+	r.Logger.Info("Setting up event handlers")
+	imcInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    impl.Enqueue,
+		UpdateFunc: controller.PassNew(impl.Enqueue),
+		DeleteFunc: impl.Enqueue,
+	})
+
+	return impl
+}
+
+// Reconciler reconciles InMemoryChannels by resolving their subscribers'
+// deadLetterSinks and installing the resulting fanout.Handler into the
+// shared multiChannel handler.
+type Reconciler struct {
+	*reconciler.Base
+
+	imcLister    messaginglisters.InMemoryChannelLister
+	multiChannel *fanout.MultiChannelHandler
+	reporter     inmemorychannel.StatsReporter
+	audit        *inmemorychannel.EventAuditLogger
+	deadLetters  *inmemorychannel.DeadLetterDispatcher
+
+	sinkResolver *resolver.URIResolver
+}
+
+// Check that our Reconciler implements controller.Reconciler.
+var _ controller.Reconciler = (*Reconciler)(nil)
+
+// Reconcile compares the actual state of an InMemoryChannel with its
+// desired state, and attempts to converge the two by installing an
+// up-to-date fanout.Handler for it.
+func (r *Reconciler) Reconcile(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		r.Logger.Errorw("Invalid resource key", zap.String("key", key), zap.Error(err))
+		return nil
+	}
+
+	imc, err := r.imcLister.InMemoryChannels(namespace).Get(name)
+	if apierrs.IsNotFound(err) {
+		r.multiChannel.RemoveChannel(namespace, name)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	imc = imc.DeepCopy()
+	reconcileErr := r.reconcile(ctx, imc)
+	if reconcileErr != nil {
+		r.Logger.Errorw("Error reconciling InMemoryChannel", zap.String("key", key), zap.Error(reconcileErr))
+	}
+	if _, updateErr := r.updateStatus(imc); updateErr != nil {
+		r.Logger.Errorw("Error updating InMemoryChannel status", zap.String("key", key), zap.Error(updateErr))
+		return updateErr
+	}
+	return reconcileErr
+}
+
+// reconcile resolves the deadLetterSink of every subscriber of imc, builds
+// the fanout.Handler that results, and installs it for imc's Channel.
+func (r *Reconciler) reconcile(ctx context.Context, imc *v1alpha1.InMemoryChannel) error {
+	var subs []fanout.Subscription
+	if imc.Spec.Subscribable != nil {
+		subs = make([]fanout.Subscription, 0, len(imc.Spec.Subscribable.Subscribers))
+		for _, s := range imc.Spec.Subscribable.Subscribers {
+			if s.SubscriberURI == nil {
+				return fmt.Errorf("subscription %s has no resolved subscriber URI", s.UID)
+			}
+
+			var deadLetterSinkURI string
+			if s.Delivery != nil && s.Delivery.DeadLetterSink != nil {
+				uri, err := r.sinkResolver.URIFromDestination(*s.Delivery.DeadLetterSink, imc)
+				if err != nil {
+					imc.Status.MarkDeadLetterSinkNotResolved(fmt.Sprintf("could not resolve deadLetterSink for subscription %s", s.UID))
+					return fmt.Errorf("unable to resolve deadLetterSink for subscription %s: %w", s.UID, err)
+				}
+				deadLetterSinkURI = uri
+			}
+
+			subs = append(subs, fanout.Subscription{
+				Name:              string(s.UID),
+				SubscriberURI:     s.SubscriberURI.String(),
+				DeadLetterSinkURI: deadLetterSinkURI,
+				Retries:           retriesFor(s),
+			})
+		}
+	}
+	imc.Status.MarkDeadLetterSinkResolved()
+
+	handler := fanout.New(imc.Namespace, imc.Name, subs, r.reporter, r.audit, r.deadLetters, r.Logger.Desugar())
+	r.multiChannel.SetChannel(imc.Namespace, imc.Name, handler)
+	return nil
+}
+
+// retriesFor returns how many times a failed delivery to s should be
+// retried before it is dead lettered, defaulting to no retries if s has no
+// Delivery spec or retry count configured.
+func retriesFor(s v1alpha1.SubscriberSpec) int {
+	if s.Delivery == nil || s.Delivery.Retry == nil {
+		return 0
+	}
+	return int(*s.Delivery.Retry)
+}
+
+func (r *Reconciler) updateStatus(desired *v1alpha1.InMemoryChannel) (*v1alpha1.InMemoryChannel, error) {
+	imc, err := r.imcLister.InMemoryChannels(desired.Namespace).Get(desired.Name)
+	if err != nil {
+		return nil, err
+	}
+	if equality.Semantic.DeepEqual(imc.Status, desired.Status) {
+		return imc, nil
+	}
+	existing := imc.DeepCopy()
+	existing.Status = desired.Status
+	return r.EventingClientSet.MessagingV1alpha1().InMemoryChannels(existing.Namespace).UpdateStatus(existing)
+}
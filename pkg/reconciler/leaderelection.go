@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// LeaderElectionConfig holds the knobs needed to run a controller's
+// reconciliation loop on only the elected leader among a set of replicas,
+// using a Kubernetes Lease as the lock.
+type LeaderElectionConfig struct {
+	// LeaseName is the name of the Lease object used to coordinate
+	// leader election.
+	LeaseName string
+
+	// LeaseNamespace is the namespace the Lease object lives in.
+	LeaseNamespace string
+
+	// LeaseDuration is the duration non-leader candidates will wait
+	// before attempting to acquire leadership.
+	LeaseDuration time.Duration
+
+	// RenewDeadline is the duration the leader will retry refreshing
+	// leadership before giving it up.
+	RenewDeadline time.Duration
+
+	// RetryPeriod is how long leader election clients should wait
+	// between tries of actions.
+	RetryPeriod time.Duration
+}
+
+// RunLeaderElected runs onStartedLeading whenever this process is elected
+// leader of the Lease described by lec, and blocks until stopCh is closed.
+// Unlike the controller's reconciliation loop, callers are expected to keep
+// serving their data plane outside of this call so that every replica,
+// leader or not, continues handling requests.
+//
+// client-go's LeaderElector.Run (which RunOrDie wraps) returns as soon as
+// this process stops being leader, whether that's because stopCh fired or
+// because a lease renewal merely failed transiently — it does not retry
+// acquiring leadership on its own. RunLeaderElected loops around RunOrDie so
+// that a transient renewal failure doesn't take the whole process down with
+// it; only a closed stopCh ends the loop.
+func RunLeaderElected(opt Options, lec LeaderElectionConfig, onStartedLeading func(stopCh <-chan struct{}), stopCh <-chan struct{}) error {
+	id, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+
+	lock, err := resourcelock.New(
+		resourcelock.LeasesResourceLock,
+		lec.LeaseNamespace,
+		lec.LeaseName,
+		opt.KubeClientSet.CoreV1(),
+		opt.KubeClientSet.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: id},
+	)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stopCh:
+			return nil
+		default:
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		go func() {
+			select {
+			case <-stopCh:
+				cancel()
+			case <-stopped:
+			}
+		}()
+
+		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+			Lock:          lock,
+			LeaseDuration: lec.LeaseDuration,
+			RenewDeadline: lec.RenewDeadline,
+			RetryPeriod:   lec.RetryPeriod,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(leCtx context.Context) {
+					opt.Logger.Infow("Acquired leadership, starting controllers", zap.String("lease", lec.LeaseName))
+					// Stop the controllers as soon as this term ends,
+					// whether that's from losing leadership or shutting
+					// down, rather than only on process shutdown.
+					termStopCh := make(chan struct{})
+					go func() {
+						defer close(termStopCh)
+						<-leCtx.Done()
+					}()
+					onStartedLeading(termStopCh)
+				},
+				OnStoppedLeading: func() {
+					opt.Logger.Infow("Lost leadership, stepping down", zap.String("lease", lec.LeaseName))
+				},
+				OnNewLeader: func(identity string) {
+					if identity != id {
+						opt.Logger.Infow("New leader elected", zap.String("leader", identity))
+					}
+				},
+			},
+		})
+		close(stopped)
+		cancel()
+	}
+}